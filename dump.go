@@ -0,0 +1,187 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package sh
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Fdump writes an indented, human-readable dump of node's type,
+// source position and field values to w. It walks File, Stmt,
+// Command, Word, WordPart, ArithmExpr, Redirect, Assign, Comment and
+// any other Node via reflection, so it keeps working as node types
+// are added or changed. Shared or cyclic pointers are only expanded
+// the first time they're reached; later occurrences are printed as
+// "(obj @ N)" back-references.
+//
+// f resolves each node's Pos/End into a source line:column, the same
+// way the printer does; pass nil to fall back to the bare, unresolved
+// Pos values instead.
+//
+// This is mainly useful for diagnosing parser and printer
+// regressions. It is a plain package-level function; nothing in this
+// package wires it to a command-line flag.
+func Fdump(w io.Writer, f *File, node Node) error {
+	p := dumper{w: w, f: f, seen: make(map[Node]int)}
+	p.dump(reflect.ValueOf(node), 0)
+	if p.err != nil {
+		return p.err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type dumper struct {
+	w    io.Writer
+	f    *File
+	seen map[Node]int
+	err  error
+}
+
+func (p *dumper) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+func (p *dumper) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		p.printf("    ")
+	}
+}
+
+// asNode reports whether v holds a non-nil value implementing Node.
+func asNode(v reflect.Value) (Node, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	n, ok := v.Interface().(Node)
+	if !ok || n == nil {
+		return nil, false
+	}
+	return n, true
+}
+
+// isLeafStruct reports whether t's exported fields are all simple
+// values (strings, ints, Pos, Token, ...), so that values of this
+// type can be dumped on a single line.
+func isLeafStruct(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		switch f.Type.Kind() {
+		case reflect.Struct, reflect.Ptr, reflect.Slice, reflect.Array, reflect.Interface, reflect.Map:
+			return false
+		}
+	}
+	return true
+}
+
+func (p *dumper) dump(v reflect.Value, depth int) {
+	if p.err != nil {
+		return
+	}
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			p.printf("nil")
+			return
+		}
+		v = v.Elem()
+	}
+	var node Node
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			p.printf("nil")
+			return
+		}
+		if n, ok := asNode(v); ok {
+			if ref, ok := p.seen[n]; ok {
+				p.printf("(obj @ %d)", ref)
+				return
+			}
+			p.seen[n] = len(p.seen)
+			node = n
+		}
+		v = v.Elem()
+	} else if n, ok := asNode(v); ok {
+		node = n
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		p.dumpStruct(v, depth, node)
+	case reflect.Slice, reflect.Array:
+		p.dumpSlice(v, depth)
+	default:
+		if v.IsValid() && v.CanInterface() {
+			p.printf("%v", v.Interface())
+		} else {
+			p.printf("<invalid>")
+		}
+	}
+}
+
+func (p *dumper) dumpStruct(v reflect.Value, depth int, node Node) {
+	t := v.Type()
+	p.printf("%s", t.Name())
+	if node != nil {
+		if p.f != nil {
+			p.printf(" @ %v-%v", p.f.Position(node.Pos()), p.f.Position(node.End()))
+		} else {
+			p.printf(" @ %v-%v", node.Pos(), node.End())
+		}
+	}
+	if isLeafStruct(t) {
+		p.printf("{")
+		first := true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if !first {
+				p.printf(", ")
+			}
+			first = false
+			p.printf("%s: ", f.Name)
+			p.dump(v.Field(i), depth)
+		}
+		p.printf("}")
+		return
+	}
+	p.printf(" {\n")
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		p.indent(depth + 1)
+		p.printf("%s: ", f.Name)
+		p.dump(v.Field(i), depth+1)
+		p.printf("\n")
+	}
+	p.indent(depth)
+	p.printf("}")
+}
+
+func (p *dumper) dumpSlice(v reflect.Value, depth int) {
+	n := v.Len()
+	if n == 0 {
+		p.printf("[]")
+		return
+	}
+	p.printf("[\n")
+	for i := 0; i < n; i++ {
+		p.indent(depth + 1)
+		p.printf("%d: ", i)
+		p.dump(v.Index(i), depth+1)
+		p.printf("\n")
+	}
+	p.indent(depth)
+	p.printf("]")
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package sh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// dumpLeaf and dumpPair are minimal stand-ins for real AST nodes,
+// just enough to implement Node, so Fdump's reflection-based walk can
+// be exercised without a parser to build a real tree.
+type dumpLeaf struct {
+	pos, end Pos
+	Name     string
+}
+
+func (n *dumpLeaf) Pos() Pos { return n.pos }
+func (n *dumpLeaf) End() Pos { return n.end }
+
+type dumpPair struct {
+	pos, end Pos
+	First    *dumpLeaf
+	Second   *dumpLeaf
+}
+
+func (n *dumpPair) Pos() Pos { return n.pos }
+func (n *dumpPair) End() Pos { return n.end }
+
+func TestFdumpSharedPointer(t *testing.T) {
+	leaf := &dumpLeaf{pos: 1, end: 2, Name: "x"}
+	root := &dumpPair{pos: 1, end: 2, First: leaf, Second: leaf}
+
+	var buf bytes.Buffer
+	if err := Fdump(&buf, nil, root); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	if !strings.Contains(got, "dumpPair") {
+		t.Errorf("dump should mention the root's type, got:\n%s", got)
+	}
+	if n := strings.Count(got, "dumpLeaf"); n != 1 {
+		t.Errorf("shared leaf should be expanded exactly once, got %d times:\n%s", n, got)
+	}
+	if !strings.Contains(got, "(obj @ 1)") {
+		t.Errorf("second reference to the shared leaf should be a back-reference, got:\n%s", got)
+	}
+}
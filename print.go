@@ -14,8 +14,101 @@ import (
 // PrintConfig controls how the printing of an AST node will behave.
 type PrintConfig struct {
 	Spaces int // 0 (default) for tabs, >0 for number of spaces
+
+	// MaxLineWidth sets the column at which long constructs such as
+	// pipelines, &&/|| chains, case pattern lists, CallExpr argument
+	// lists, arithmetic expressions and for/in word lists are allowed
+	// to wrap onto further lines instead of following the newlines of
+	// the original source. 0 (the default) disables wrapping and keeps
+	// the previous, source-driven behavior.
+	MaxLineWidth int
+
+	// Align, when true, lines up trailing "#" comments, the =/+=
+	// columns of consecutive bare assignment statements, and the )/;;
+	// columns of consecutive one-line case arms, within each
+	// contiguous block of similarly-shaped lines.
+	Align bool
+
+	// Minify, when true, prints the smallest legal script equivalent
+	// to the input: statements are separated with "; " instead of
+	// newlines wherever the grammar allows it, single-line
+	// expansion-free heredocs are rewritten as "<<<" here-strings,
+	// comments are dropped, and no indentation is emitted.
+	Minify bool
+
+	// MaxBlankLines caps the number of consecutive blank lines kept
+	// from the original source. Its zero value keeps the package's
+	// original behavior: a run of blank lines collapses down to a
+	// single one. Set it to a positive N to keep up to N blank lines
+	// instead, to -1 to preserve the source's blank-line gaps exactly,
+	// or to any value below -1 to drop blank lines entirely.
+	MaxBlankLines int
+
+	// Highlight wraps emitted tokens in classification markup, so the
+	// output can be rendered as syntax-highlighted HTML or colored
+	// terminal text. It is zero-cost when left at its default,
+	// HighlightNone.
+	Highlight Highlight
+
+	// ANSIStyles overrides the SGR sequence used for a given
+	// HighlightClass when Highlight is HighlightANSI. Classes left
+	// unset fall back to the package's default styles.
+	ANSIStyles map[HighlightClass]string
+}
+
+// Highlight selects how PrintConfig.Fprint marks up its output for
+// syntax highlighting.
+type Highlight int
+
+const (
+	// HighlightNone prints plain output, with no markup at all.
+	HighlightNone Highlight = iota
+	// HighlightHTML wraps classified tokens in <span class="sh-..."> tags
+	// and HTML-escapes all text.
+	HighlightHTML
+	// HighlightANSI wraps classified tokens in SGR escape sequences.
+	HighlightANSI
+)
+
+// HighlightClass identifies a lexical category of printed output, used
+// by PrintConfig.Highlight to classify emitted tokens.
+type HighlightClass int
+
+const (
+	HighlightKeyword HighlightClass = iota
+	HighlightOp
+	HighlightString
+	HighlightParam
+	HighlightCmdSubst
+	HighlightArithm
+	HighlightComment
+	HighlightHeredoc
+)
+
+var highlightHTMLClasses = map[HighlightClass]string{
+	HighlightKeyword:  "sh-kw",
+	HighlightOp:       "sh-op",
+	HighlightString:   "sh-str",
+	HighlightParam:    "sh-param",
+	HighlightCmdSubst: "sh-cmdsubst",
+	HighlightArithm:   "sh-arithm",
+	HighlightComment:  "sh-comment",
+	HighlightHeredoc:  "sh-heredoc",
+}
+
+var defaultANSIStyles = map[HighlightClass]string{
+	HighlightKeyword:  "\x1b[34m", // blue
+	HighlightOp:       "\x1b[36m", // cyan
+	HighlightString:   "\x1b[32m", // green
+	HighlightParam:    "\x1b[35m", // magenta
+	HighlightCmdSubst: "\x1b[35m", // magenta
+	HighlightArithm:   "\x1b[35m", // magenta
+	HighlightComment:  "\x1b[90m", // bright black
+	HighlightHeredoc:  "\x1b[32m", // green
 }
 
+const ansiReset = "\x1b[0m"
+
 var writerFree = sync.Pool{
 	New: func() interface{} { return bufio.NewWriter(nil) },
 }
@@ -29,10 +122,22 @@ func (c PrintConfig) Fprint(w io.Writer, f *File) error {
 		f: f,
 		c: c,
 	}
+	if c.Align {
+		p.elastic = newElasticWriter(bw, c.Spaces)
+		p.out = p.elastic
+	} else {
+		p.out = p.w
+	}
 	p.comments = f.Comments
 	p.stmts(f.Stmts)
 	p.commentsUpTo(0)
 	p.newline()
+	if p.elastic != nil {
+		p.elastic.flushBlock()
+		if p.err == nil {
+			p.err = p.elastic.err
+		}
+	}
 	if p.err == nil {
 		p.err = bw.Flush()
 	}
@@ -58,6 +163,31 @@ type printer struct {
 	c   PrintConfig
 	err error
 
+	// out is the sink that the low-level write helpers (str, byte,
+	// token, ...) actually write to. It is p.w (or p.elastic, when
+	// PrintConfig.Align is set) outside of any fill group, and the
+	// buffer of the innermost open group while one is
+	// active, so that a group's contents can be measured before its
+	// break points are resolved.
+	out bufWriter
+	// col is the current output column, as if every open group were
+	// rendered flat. It resets on any literal newline written via
+	// writeByte/writeString.
+	col int
+	// groups is the stack of fill-mode groups currently being
+	// buffered; see openGroup/brk/closeGroup.
+	groups []*printGroup
+
+	// elastic is non-nil when PrintConfig.Align is set; it sits at the
+	// bottom of the p.out chain and performs the actual column
+	// alignment once a block of lines is known.
+	elastic *elasticWriter
+
+	// hlStack is the nesting stack of currently open PrintConfig.Highlight
+	// classes, used to restore the enclosing class's ANSI color once an
+	// inner one ends.
+	hlStack []HighlightClass
+
 	nestedBinary bool
 
 	wantSpace   bool
@@ -82,46 +212,155 @@ type printer struct {
 	pendingHdocs []*Redirect
 }
 
+// writeString writes s to the current sink (p.out) and keeps p.col in
+// sync, as if s were always rendered flat; any group wrapping s will
+// fix p.col up again once its break points are resolved.
+func (p *printer) writeString(s string) {
+	if p.c.Highlight == HighlightHTML {
+		s = htmlEscape(s)
+	}
+	p.writeRaw(s)
+}
+
+// writeByte is the byte counterpart of writeString.
+func (p *printer) writeByte(b byte) {
+	if p.c.Highlight == HighlightHTML {
+		switch b {
+		case '<':
+			p.writeRaw("&lt;")
+			return
+		case '>':
+			p.writeRaw("&gt;")
+			return
+		case '&':
+			p.writeRaw("&amp;")
+			return
+		}
+	}
+	if b == '\n' {
+		p.col = 0
+	} else {
+		p.col++
+	}
+	p.err = p.out.WriteByte(b)
+}
+
+// writeRaw writes s to the current sink verbatim, bypassing HTML
+// escaping - used for markup the printer itself generates (highlight
+// tags) rather than script text.
+func (p *printer) writeRaw(s string) {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = len(s) - i - 1
+	} else {
+		p.col += len(s)
+	}
+	_, p.err = p.out.WriteString(s)
+}
+
+// htmlEscape escapes the characters HTML requires within text content.
+func htmlEscape(s string) string {
+	if !strings.ContainsAny(s, "&<>") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return s
+}
+
+// beginHighlight opens classification markup for c, pushing it onto
+// the nesting stack so ANSI output can restore the enclosing class's
+// color (if any) once this one ends.
+func (p *printer) beginHighlight(c HighlightClass) {
+	switch p.c.Highlight {
+	case HighlightHTML:
+		p.writeRaw(`<span class="` + highlightHTMLClasses[c] + `">`)
+	case HighlightANSI:
+		p.writeRaw(p.ansiStyle(c))
+	default:
+		return
+	}
+	p.hlStack = append(p.hlStack, c)
+}
+
+// endHighlight closes the markup opened by the matching beginHighlight.
+func (p *printer) endHighlight() {
+	switch p.c.Highlight {
+	case HighlightHTML:
+		p.writeRaw("</span>")
+	case HighlightANSI:
+		p.hlStack = p.hlStack[:len(p.hlStack)-1]
+		if len(p.hlStack) == 0 {
+			p.writeRaw(ansiReset)
+		} else {
+			p.writeRaw(p.ansiStyle(p.hlStack[len(p.hlStack)-1]))
+		}
+		return
+	default:
+		return
+	}
+	p.hlStack = p.hlStack[:len(p.hlStack)-1]
+}
+
+func (p *printer) ansiStyle(c HighlightClass) string {
+	if s, ok := p.c.ANSIStyles[c]; ok {
+		return s
+	}
+	return defaultANSIStyles[c]
+}
+
+// hl writes s classified as c; when Highlight is HighlightNone this
+// costs nothing beyond the ordinary write.
+func (p *printer) hl(c HighlightClass, s string) {
+	if p.c.Highlight == HighlightNone {
+		p.writeString(s)
+		return
+	}
+	p.beginHighlight(c)
+	p.writeString(s)
+	p.endHighlight()
+}
+
 func (p *printer) space() {
-	p.err = p.w.WriteByte(' ')
+	p.writeByte(' ')
 	p.wantSpace = false
 }
 
 func (p *printer) spaces(n int) {
 	for i := 0; i < n; i++ {
-		p.w.WriteByte(' ')
+		p.writeByte(' ')
 	}
 	p.wantSpace = false
 }
 
 func (p *printer) tabs(n int) {
 	for i := 0; i < n; i++ {
-		p.w.WriteByte('\t')
+		p.writeByte('\t')
 	}
 	p.wantSpace = false
 }
 
 func (p *printer) bslashNewl() {
-	_, p.err = p.w.WriteString(" \\\n")
+	p.writeString(" \\\n")
 	p.wantSpace = false
 	p.curLine++
 }
 
 func (p *printer) str(s string) {
-	_, p.err = p.w.WriteString(s)
+	p.writeString(s)
 }
 
 func (p *printer) byte(b byte) {
-	p.err = p.w.WriteByte(b)
+	p.writeByte(b)
 }
 
 func (p *printer) token(s string, spaceAfter bool) {
 	p.wantSpace = spaceAfter
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightOp, s)
 }
 
 func (p *printer) rsrv(s string) {
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightKeyword, s)
 	p.wantSpace = true
 }
 
@@ -129,7 +368,7 @@ func (p *printer) spacedRsrv(s string) {
 	if p.wantSpace {
 		p.space()
 	}
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightKeyword, s)
 	p.wantSpace = true
 }
 
@@ -138,21 +377,279 @@ func (p *printer) spacedTok(s string, spaceAfter bool) {
 		p.space()
 	}
 	p.wantSpace = spaceAfter
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightOp, s)
 }
 
 func (p *printer) semiOrNewl(s string, pos Pos) {
-	if p.wantNewline {
+	if p.wantNewline && !p.c.Minify {
 		p.newline()
 		p.indent()
 	} else {
 		p.str("; ")
 	}
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightKeyword, s)
 	p.wantSpace = true
 	p.curLine = p.f.Position(pos).Line
 }
 
+// breakKind identifies how a group's break point renders once its flat
+// form is measured against PrintConfig.MaxLineWidth.
+type breakKind int
+
+const (
+	// breakSpace is a single space when flat, and a newline plus one
+	// more level of indentation than the enclosing code when broken.
+	breakSpace breakKind = iota
+	// breakLine is a single space when flat, and a backslash-newline
+	// plus indent when broken; used wherever the grammar requires an
+	// explicit line continuation rather than a bare newline.
+	breakLine
+	// breakSemi is "; " when flat, and a newline plus indent when
+	// broken.
+	breakSemi
+)
+
+// groupKind selects how a group resolves its break points once it
+// doesn't fit within PrintConfig.MaxLineWidth.
+type groupKind int
+
+const (
+	// groupConsistent breaks either all of its break points or none.
+	groupConsistent groupKind = iota
+	// groupInconsistent greedily promotes a break point only once the
+	// line has already reached the margin (fill mode); the segment
+	// that reaches it stays on that line, so a line can run past the
+	// margin.
+	groupInconsistent
+)
+
+// groupBreak records one break point buffered within a printGroup.
+type groupBreak struct {
+	offset int // byte offset into the group's buffer where its flat form begins
+	kind   breakKind
+}
+
+// printGroup buffers one nesting level of a fill-mode construct (a
+// wordJoin, a binary command chain, a case pattern list, ...) so that
+// its total width can be measured before its break points are
+// resolved. A group holds everything printed between its openGroup
+// and closeGroup, including the resolved output of groups nested in
+// it, so a group around a large construct buffers all of it.
+type printGroup struct {
+	buf      bytes.Buffer
+	out      bufWriter // the sink to flush into once resolved
+	kind     groupKind
+	breaks   []groupBreak
+	startCol int // p.col as it was when the group was opened
+}
+
+// openGroup starts buffering output into a new group. It is a no-op
+// when PrintConfig.MaxLineWidth is 0, so callers can freely wrap their
+// existing source-driven code paths without changing behavior.
+func (p *printer) openGroup(kind groupKind) {
+	if p.c.MaxLineWidth <= 0 {
+		return
+	}
+	g := &printGroup{kind: kind, out: p.out, startCol: p.col}
+	p.groups = append(p.groups, g)
+	p.out = &g.buf
+}
+
+// brk records a break point in the innermost open group. Its flat
+// rendering is written immediately; closeGroup decides later whether
+// to promote it to a line break.
+func (p *printer) brk(kind breakKind) {
+	if p.c.MaxLineWidth <= 0 || len(p.groups) == 0 {
+		return
+	}
+	g := p.groups[len(p.groups)-1]
+	g.breaks = append(g.breaks, groupBreak{offset: g.buf.Len(), kind: kind})
+	if kind == breakSemi {
+		p.writeString("; ")
+	} else {
+		p.space()
+	}
+}
+
+func breakFlatLen(kind breakKind) int {
+	if kind == breakSemi {
+		return 2
+	}
+	return 1
+}
+
+// closeGroup ends the innermost open group, measuring its buffered
+// width against PrintConfig.MaxLineWidth and flushing it to the
+// enclosing sink, promoting its break points to line breaks if it
+// doesn't fit.
+func (p *printer) closeGroup() {
+	if p.c.MaxLineWidth <= 0 {
+		return
+	}
+	g := p.groups[len(p.groups)-1]
+	p.groups = p.groups[:len(p.groups)-1]
+	p.out = g.out
+	flat := g.buf.Bytes()
+	// Buffering a group's content still ran it through writeString/
+	// writeByte, which advanced p.col as if it were flat output; undo
+	// that before replaying so the real emission (flat copy or
+	// emitBroken) accounts for it exactly once.
+	p.col = g.startCol
+	// The buffer was already escaped and marked up on its way in, so
+	// it's replayed with writeRaw rather than escaped a second time.
+	if len(g.breaks) == 0 || g.startCol+len(flat) <= p.c.MaxLineWidth {
+		p.writeRaw(g.buf.String())
+		return
+	}
+	p.emitBroken(g, flat, g.kind == groupConsistent)
+}
+
+// emitBroken walks a group's buffered, flat-rendered content and
+// writes it to the enclosing sink, promoting break points to line
+// breaks. If all is true every break point is promoted (consistent
+// mode); otherwise a break point is only promoted once the column
+// already reached the margin (inconsistent / fill mode).
+func (p *printer) emitBroken(g *printGroup, flat []byte, all bool) {
+	pos := 0
+	anyBreak := false
+	for _, b := range g.breaks {
+		p.writeRaw(string(flat[pos:b.offset]))
+		n := breakFlatLen(b.kind)
+		if all || p.col >= p.c.MaxLineWidth {
+			if !anyBreak {
+				p.incLevel()
+				anyBreak = true
+			}
+			if b.kind == breakLine && len(p.pendingHdocs) == 0 {
+				p.writeString(" \\\n")
+			} else {
+				// newline, not a bare '\n', since a command
+				// before this break point may have queued a
+				// heredoc body that needs to land right here
+				// rather than wherever the next unrelated
+				// newline happens to fire.
+				p.newline()
+			}
+			p.indent()
+		} else {
+			p.writeRaw(string(flat[b.offset : b.offset+n]))
+		}
+		pos = b.offset + n
+	}
+	p.writeRaw(string(flat[pos:]))
+	if anyBreak {
+		p.decLevel()
+	}
+}
+
+// elasticSentinel marks an elastic-tabstop cell boundary recorded
+// while PrintConfig.Align is enabled. It never reaches the final
+// output; elasticWriter consumes it to compute column widths.
+const elasticSentinel = '\x1f'
+
+// elasticWriter sits between the printer and the real output, buffering
+// whole lines so that contiguous blocks of similarly-shaped lines (the
+// same number of elasticSentinel-separated cells) can have their
+// columns aligned once the block ends - at a blank line, at a line
+// whose cell count differs from the block's, or when the writer is
+// flushed at the end of printing.
+type elasticWriter struct {
+	out    bufWriter
+	spaces int // PrintConfig.Spaces; 0 joins cells with a literal tab
+	line   bytes.Buffer
+	block  [][]string
+	err    error
+}
+
+func newElasticWriter(out bufWriter, spaces int) *elasticWriter {
+	return &elasticWriter{out: out, spaces: spaces}
+}
+
+func (e *elasticWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if err := e.WriteByte(b); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (e *elasticWriter) WriteString(s string) (int, error) {
+	for i := 0; i < len(s); i++ {
+		if err := e.WriteByte(s[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(s), nil
+}
+
+func (e *elasticWriter) WriteByte(b byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	if b != '\n' {
+		return e.line.WriteByte(b)
+	}
+	text := e.line.String()
+	e.line.Reset()
+	if strings.TrimSpace(text) == "" {
+		e.flushBlock()
+		e.writeRaw(text)
+		e.writeRaw("\n")
+		return e.err
+	}
+	cells := strings.Split(text, string(elasticSentinel))
+	if len(e.block) > 0 && len(e.block[len(e.block)-1]) != len(cells) {
+		e.flushBlock()
+	}
+	e.block = append(e.block, cells)
+	return e.err
+}
+
+// flushBlock computes the per-column width across the buffered block
+// and writes it out, replacing each cell boundary with padded spaces
+// (PrintConfig.Spaces > 0) or a literal tab (Spaces == 0, left for an
+// elastic-tabstop-aware viewer to line up).
+func (e *elasticWriter) flushBlock() {
+	if len(e.block) == 0 {
+		return
+	}
+	n := len(e.block[0])
+	var widths []int
+	if e.spaces > 0 && n > 1 {
+		widths = make([]int, n-1)
+		for _, cells := range e.block {
+			for i := 0; i < n-1; i++ {
+				if w := len([]rune(cells[i])); w > widths[i] {
+					widths[i] = w
+				}
+			}
+		}
+	}
+	for _, cells := range e.block {
+		for i, c := range cells {
+			e.writeRaw(c)
+			if i == n-1 {
+				continue
+			}
+			if e.spaces > 0 {
+				e.writeRaw(strings.Repeat(" ", widths[i]-len([]rune(c))+1))
+			} else {
+				e.writeRaw("\t")
+			}
+		}
+		e.writeRaw("\n")
+	}
+	e.block = e.block[:0]
+}
+
+func (e *elasticWriter) writeRaw(s string) {
+	if e.err != nil || s == "" {
+		return
+	}
+	_, e.err = e.out.WriteString(s)
+}
+
 func (p *printer) incLevel() {
 	inc := false
 	if p.level <= p.lastLevel {
@@ -174,6 +671,9 @@ func (p *printer) decLevel() {
 }
 
 func (p *printer) indent() {
+	if p.c.Minify {
+		return
+	}
 	p.lastLevel = p.level
 	switch {
 	case p.level == 0:
@@ -186,13 +686,17 @@ func (p *printer) indent() {
 
 func (p *printer) newline() {
 	p.wantNewline = false
-	p.err = p.w.WriteByte('\n')
+	if !p.c.Minify || len(p.pendingHdocs) > 0 {
+		p.writeByte('\n')
+	}
 	p.wantSpace = false
 	for _, r := range p.pendingHdocs {
-		p.str(r.Hdoc.Value)
+		p.beginHighlight(HighlightHeredoc)
+		p.writeString(r.Hdoc.Value)
+		p.endHighlight()
 		p.curLine += strings.Count(r.Hdoc.Value, "\n")
 		p.unquotedWord(&r.Word)
-		p.err = p.w.WriteByte('\n')
+		p.writeByte('\n')
 		p.curLine++
 		p.wantSpace = false
 	}
@@ -201,9 +705,31 @@ func (p *printer) newline() {
 
 func (p *printer) newlines(pos Position) {
 	p.newline()
-	if pos.Line > p.curLine+1 {
-		// preserve single empty lines
-		p.err = p.w.WriteByte('\n')
+	if p.c.Minify {
+		p.curLine = pos.Line
+		return
+	}
+	if gap := pos.Line - p.curLine - 1; gap > 0 {
+		switch {
+		case p.c.MaxBlankLines == 0:
+			// the zero value: collapse any run down to one blank
+			// line, same as before MaxBlankLines existed.
+			p.writeByte('\n')
+		case p.c.MaxBlankLines == -1:
+			// preserve the source's blank lines exactly
+			for i := 0; i < gap; i++ {
+				p.writeByte('\n')
+			}
+		case p.c.MaxBlankLines < -1:
+			// drop blank lines entirely.
+		case p.c.MaxBlankLines > 0:
+			if gap > p.c.MaxBlankLines {
+				gap = p.c.MaxBlankLines
+			}
+			for i := 0; i < gap; i++ {
+				p.writeByte('\n')
+			}
+		}
 	}
 	p.indent()
 	p.curLine = pos.Line
@@ -240,7 +766,7 @@ func (p *printer) sepTok(s string, pos Position) {
 	if s != ")" && p.wantSpace {
 		p.space()
 	}
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightOp, s)
 	p.wantSpace = true
 }
 
@@ -254,7 +780,7 @@ func (p *printer) semiRsrv(s string, rpos Pos, fallback bool) {
 	} else if p.wantSpace {
 		p.space()
 	}
-	_, p.err = p.w.WriteString(s)
+	p.hl(HighlightKeyword, s)
 	p.wantSpace = true
 }
 
@@ -283,12 +809,23 @@ func (p *printer) commentsUpTo(line int) {
 	if line > 0 && cpos.Line >= line {
 		return
 	}
+	if p.c.Minify {
+		p.comments = p.comments[1:]
+		p.commentsUpTo(line)
+		return
+	}
 	p.wantNewline = false
 	if !p.didSeparate(cpos) {
-		p.spaces(p.wantSpaces + 1)
+		if p.c.Align {
+			p.writeByte(elasticSentinel)
+		} else {
+			p.spaces(p.wantSpaces + 1)
+		}
 	}
-	p.err = p.w.WriteByte('#')
-	_, p.err = p.w.WriteString(c.Text)
+	p.beginHighlight(HighlightComment)
+	p.writeByte('#')
+	p.writeString(c.Text)
+	p.endHighlight()
 	p.comments = p.comments[1:]
 	p.commentsUpTo(line)
 }
@@ -340,21 +877,26 @@ func expansionOp(tok Token) string {
 func (p *printer) wordPart(wp WordPart) {
 	switch x := wp.(type) {
 	case *Lit:
-		_, p.err = p.w.WriteString(x.Value)
+		p.writeString(x.Value)
 	case *SglQuoted:
+		p.beginHighlight(HighlightString)
 		p.byte('\'')
-		_, p.err = p.w.WriteString(x.Value)
+		p.writeString(x.Value)
 		p.curLine += strings.Count(x.Value, "\n")
 		p.byte('\'')
+		p.endHighlight()
 	case *Quoted:
+		p.beginHighlight(HighlightString)
 		p.str(quotedOp(x.Quote))
 		for _, n := range x.Parts {
 			p.wordPart(n)
 			p.curLine = p.f.Position(n.End()).Line
 		}
 		p.str(quotedOp(quotedStop(x.Quote)))
+		p.endHighlight()
 	case *CmdSubst:
 		p.wantSpace = false
+		p.beginHighlight(HighlightCmdSubst)
 		if x.Backquotes {
 			p.byte('`')
 		} else {
@@ -370,10 +912,13 @@ func (p *printer) wordPart(wp WordPart) {
 		} else {
 			p.sepTok(")", p.f.Position(x.Right))
 		}
+		p.endHighlight()
 	case *ParamExp:
+		p.beginHighlight(HighlightParam)
 		if x.Short {
 			p.byte('$')
 			p.str(x.Param.Value)
+			p.endHighlight()
 			break
 		}
 		p.str("${")
@@ -399,10 +944,13 @@ func (p *printer) wordPart(wp WordPart) {
 			p.word(x.Exp.Word)
 		}
 		p.byte('}')
+		p.endHighlight()
 	case *ArithmExp:
+		p.beginHighlight(HighlightArithm)
 		p.str("$((")
 		p.arithm(x.X, false)
 		p.str("))")
+		p.endHighlight()
 	case *ArrayExpr:
 		p.wantSpace = false
 		p.byte('(')
@@ -548,23 +1096,39 @@ func (p *printer) arithm(expr ArithmExpr, compact bool) {
 	case *BinaryExpr:
 		if compact {
 			p.arithm(x.X, true)
-			p.str(binaryExprOp(x.Op))
+			p.hl(HighlightOp, binaryExprOp(x.Op))
 			p.arithm(x.Y, true)
+		} else if p.c.MaxLineWidth > 0 {
+			// Arithmetic contexts ($(( )), (( )), the C-style for's
+			// three clauses) are always enclosed in their own parens,
+			// so a bare newline - not a backslash-newline - is a safe
+			// break point here.
+			p.openGroup(groupInconsistent)
+			p.arithm(x.X, false)
+			if x.Op != COMMA {
+				p.brk(breakSpace)
+			} else {
+				p.space()
+			}
+			p.hl(HighlightOp, binaryExprOp(x.Op))
+			p.brk(breakSpace)
+			p.arithm(x.Y, false)
+			p.closeGroup()
 		} else {
 			p.arithm(x.X, false)
 			if x.Op != COMMA {
 				p.space()
 			}
-			p.str(binaryExprOp(x.Op))
+			p.hl(HighlightOp, binaryExprOp(x.Op))
 			p.space()
 			p.arithm(x.Y, false)
 		}
 	case *UnaryExpr:
 		if x.Post {
 			p.arithm(x.X, compact)
-			p.str(unaryExprOp(x.Op))
+			p.hl(HighlightOp, unaryExprOp(x.Op))
 		} else {
-			p.str(unaryExprOp(x.Op))
+			p.hl(HighlightOp, unaryExprOp(x.Op))
 			p.arithm(x.X, compact)
 		}
 	case *ParenExpr:
@@ -611,13 +1175,30 @@ func (p *printer) spacedWord(w Word) {
 }
 
 func (p *printer) wordJoin(ws []Word, needBackslash bool) {
+	if p.c.MaxLineWidth > 0 {
+		brkKind := breakSpace
+		if needBackslash {
+			brkKind = breakLine
+		}
+		p.openGroup(groupInconsistent)
+		for i, w := range ws {
+			if i > 0 || p.wantSpace {
+				p.brk(brkKind)
+			}
+			for _, n := range w.Parts {
+				p.wordPart(n)
+			}
+		}
+		p.closeGroup()
+		return
+	}
 	anyNewline := false
 	for _, w := range ws {
 		if p.curLine > 0 && p.f.Position(w.Pos()).Line > p.curLine {
 			if needBackslash {
 				p.bslashNewl()
 			} else {
-				p.err = p.w.WriteByte('\n')
+				p.writeByte('\n')
 				p.curLine++
 			}
 			if !anyNewline {
@@ -641,7 +1222,11 @@ func (p *printer) stmt(s *Stmt) {
 	if s.Negated {
 		p.spacedRsrv("!")
 	}
-	p.assigns(s.Assigns)
+	if p.c.Align && !s.Negated && s.Cmd == nil && len(s.Assigns) == 1 && len(s.Redirs) == 0 {
+		p.bareAssign(s.Assigns[0])
+	} else {
+		p.assigns(s.Assigns)
+	}
 	startRedirs := p.command(s.Cmd, s.Redirs)
 	anyNewline := false
 	for _, r := range s.Redirs[startRedirs:] {
@@ -661,11 +1246,19 @@ func (p *printer) stmt(s *Stmt) {
 		if r.N != nil {
 			p.str(r.N.Value)
 		}
-		p.str(redirectOp(r.Op))
-		p.wantSpace = true
-		p.word(r.Word)
-		if r.Op == SHL || r.Op == DHEREDOC {
-			p.pendingHdocs = append(p.pendingHdocs, r)
+		if p.c.Minify && minifiableHdoc(r) {
+			p.hl(HighlightOp, redirectOp(WHEREDOC))
+			p.space()
+			p.byte('"')
+			p.str(strings.TrimSuffix(r.Hdoc.Value, "\n"))
+			p.byte('"')
+		} else {
+			p.hl(HighlightOp, redirectOp(r.Op))
+			p.wantSpace = true
+			p.word(r.Word)
+			if r.Op == SHL || r.Op == DHEREDOC {
+				p.pendingHdocs = append(p.pendingHdocs, r)
+			}
 		}
 	}
 	if anyNewline {
@@ -676,6 +1269,21 @@ func (p *printer) stmt(s *Stmt) {
 	}
 }
 
+// minifiableHdoc reports whether r's heredoc body can be losslessly
+// rewritten as a `<<< "…"` here-string: exactly one line, with no
+// characters a shell would expand at runtime, and none that would
+// conflict with the double quotes the here-string is wrapped in.
+func minifiableHdoc(r *Redirect) bool {
+	if r.Op != SHL && r.Op != DHEREDOC {
+		return false
+	}
+	body := r.Hdoc.Value
+	if !strings.HasSuffix(body, "\n") || strings.Count(body, "\n") != 1 {
+		return false
+	}
+	return !strings.ContainsAny(body[:len(body)-1], "$`\"\\")
+}
+
 func redirectOp(tok Token) string {
 	switch tok {
 	case LSS:
@@ -748,7 +1356,7 @@ func (p *printer) command(cmd Command, redirs []*Redirect) (startRedirs int) {
 			if r.N != nil {
 				p.str(r.N.Value)
 			}
-			p.str(redirectOp(r.Op))
+			p.hl(HighlightOp, redirectOp(r.Op))
 			p.wantSpace = true
 			p.word(r.Word)
 			startRedirs++
@@ -796,25 +1404,41 @@ func (p *printer) command(cmd Command, redirs []*Redirect) (startRedirs int) {
 		p.nestedStmts(x.DoStmts)
 		p.semiRsrv("done", x.Done, true)
 	case *BinaryCmd:
+		isChainStart := !p.nestedBinary
+		if p.c.MaxLineWidth > 0 && isChainStart {
+			p.openGroup(groupConsistent)
+		}
 		p.stmt(x.X)
-		indent := !p.nestedBinary
+		indent := isChainStart
 		if indent {
 			p.incLevel()
 		}
 		_, p.nestedBinary = x.Y.Cmd.(*BinaryCmd)
 		ypos := p.f.Position(x.Y.Pos())
-		if len(p.pendingHdocs) > 0 {
-		} else if ypos.Line > p.curLine {
-			p.bslashNewl()
-			p.indent()
+		if p.c.MaxLineWidth > 0 {
+			// A control operator at the end of a line doesn't need a
+			// backslash to continue onto the next - the shell already
+			// knows a command must follow.
+			p.curLine = ypos.Line
+			p.spacedTok(binaryCmdOp(x.Op), false)
+			p.brk(breakSpace)
+		} else {
+			if len(p.pendingHdocs) > 0 {
+			} else if ypos.Line > p.curLine {
+				p.bslashNewl()
+				p.indent()
+			}
+			p.curLine = ypos.Line
+			p.spacedTok(binaryCmdOp(x.Op), true)
 		}
-		p.curLine = ypos.Line
-		p.spacedTok(binaryCmdOp(x.Op), true)
 		p.stmt(x.Y)
 		if indent {
 			p.decLevel()
 		}
 		p.nestedBinary = false
+		if p.c.MaxLineWidth > 0 && isChainStart {
+			p.closeGroup()
+		}
 	case *FuncDecl:
 		if x.BashStyle {
 			p.str("function ")
@@ -829,11 +1453,26 @@ func (p *printer) command(cmd Command, redirs []*Redirect) (startRedirs int) {
 		p.incLevel()
 		for _, pl := range x.List {
 			p.didSeparate(p.f.Position(wordFirstPos(pl.Patterns)))
-			for i, w := range pl.Patterns {
-				if i > 0 {
-					p.spacedTok("|", true)
+			if p.c.MaxLineWidth > 0 {
+				p.openGroup(groupConsistent)
+				for i, w := range pl.Patterns {
+					if i > 0 {
+						p.brk(breakLine)
+						p.str("| ")
+					}
+					p.spacedWord(w)
 				}
-				p.spacedWord(w)
+				p.closeGroup()
+			} else {
+				for i, w := range pl.Patterns {
+					if i > 0 {
+						p.spacedTok("|", true)
+					}
+					p.spacedWord(w)
+				}
+			}
+			if p.c.Align {
+				p.writeByte(elasticSentinel)
 			}
 			p.byte(')')
 			sep := p.nestedStmts(pl.Stmts)
@@ -841,6 +1480,9 @@ func (p *printer) command(cmd Command, redirs []*Redirect) (startRedirs int) {
 			opPos := p.f.Position(pl.OpPos)
 			if !sep {
 				p.curLine++
+				if p.c.Align {
+					p.writeByte(elasticSentinel)
+				}
 			} else if opPos.Line == p.curLine && pl.OpPos != x.Esac {
 				p.curLine--
 			}
@@ -875,9 +1517,18 @@ func (p *printer) command(cmd Command, redirs []*Redirect) (startRedirs int) {
 		}
 	case *LetClause:
 		p.spacedRsrv("let")
-		for _, n := range x.Exprs {
-			p.space()
-			p.arithm(n, true)
+		if p.c.MaxLineWidth > 0 {
+			p.openGroup(groupInconsistent)
+			for _, n := range x.Exprs {
+				p.brk(breakLine)
+				p.arithm(n, true)
+			}
+			p.closeGroup()
+		} else {
+			for _, n := range x.Exprs {
+				p.space()
+				p.arithm(n, true)
+			}
 		}
 	}
 	return startRedirs
@@ -902,6 +1553,34 @@ func (p *printer) stmts(stmts []*Stmt) bool {
 		p.stmt(s)
 		return false
 	}
+	if p.c.Minify {
+		for i, s := range stmts {
+			if i > 0 {
+				if len(p.pendingHdocs) > 0 {
+					// A non-minifiable heredoc body must be
+					// flushed on its own lines before any
+					// further statement, or it would be
+					// dumped in the wrong place.
+					p.newline()
+				} else if stmts[i-1].Background {
+					// "&" already ends the statement; "&;"
+					// would not parse.
+					p.space()
+				} else {
+					p.str("; ")
+					p.wantSpace = false
+				}
+			}
+			p.commentsUpTo(0)
+			p.curLine = p.f.Position(s.Pos()).Line
+			p.stmt(s)
+		}
+		if len(p.pendingHdocs) > 0 {
+			p.newline()
+		}
+		p.wantNewline = false
+		return true
+	}
 	inlineIndent := 0
 	lastLine := pos.Line
 	for i, s := range stmts {
@@ -940,6 +1619,7 @@ func (p *printer) stmts(stmts []*Stmt) bool {
 func unquotedWordStr(f *File, w *Word) string {
 	var buf bytes.Buffer
 	p := printer{w: &buf, f: f}
+	p.out = p.w
 	p.unquotedWord(w)
 	return buf.String()
 }
@@ -947,6 +1627,7 @@ func unquotedWordStr(f *File, w *Word) string {
 func wordStr(f *File, w Word) string {
 	var buf bytes.Buffer
 	p := printer{w: &buf, f: f}
+	p.out = p.w
 	p.word(w)
 	return buf.String()
 }
@@ -954,6 +1635,7 @@ func wordStr(f *File, w Word) string {
 func stmtLen(f *File, s *Stmt) int {
 	var buf bytes.Buffer
 	p := printer{w: &buf, f: f}
+	p.out = p.w
 	p.stmt(s)
 	return buf.Len()
 }
@@ -992,3 +1674,20 @@ func (p *printer) assigns(assigns []*Assign) {
 		p.decLevel()
 	}
 }
+
+// bareAssign prints a statement consisting of a single assignment and
+// nothing else, inserting an elastic-tabstop cell boundary before its
+// =/+= so that PrintConfig.Align can line up consecutive bare
+// assignments' operator columns.
+func (p *printer) bareAssign(a *Assign) {
+	if a.Name != nil {
+		p.str(a.Name.Value)
+		p.writeByte(elasticSentinel)
+		if a.Append {
+			p.token("+=", true)
+		} else {
+			p.token("=", true)
+		}
+	}
+	p.word(a.Value)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package sh
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHTMLEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"<b>bold</b>", "&lt;b&gt;bold&lt;/b&gt;"},
+		{"a && b", "a &amp;&amp; b"},
+		{"<&>", "&lt;&amp;&gt;"},
+	}
+	for _, tc := range tests {
+		if got := htmlEscape(tc.in); got != tc.want {
+			t.Errorf("htmlEscape(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestAnsiStyle(t *testing.T) {
+	p := &printer{}
+	if got, want := p.ansiStyle(HighlightKeyword), defaultANSIStyles[HighlightKeyword]; got != want {
+		t.Errorf("ansiStyle with no overrides = %q, want default %q", got, want)
+	}
+	p.c.ANSIStyles = map[HighlightClass]string{HighlightKeyword: "\x1b[1m"}
+	if got, want := p.ansiStyle(HighlightKeyword), "\x1b[1m"; got != want {
+		t.Errorf("ansiStyle override = %q, want %q", got, want)
+	}
+	// A class left unset in ANSIStyles still falls back to the default.
+	if got, want := p.ansiStyle(HighlightOp), defaultANSIStyles[HighlightOp]; got != want {
+		t.Errorf("ansiStyle unset override = %q, want default %q", got, want)
+	}
+}
+
+func TestMinifiableHdoc(t *testing.T) {
+	tests := []struct {
+		name string
+		op   Token
+		body string
+		want bool
+	}{
+		{"plain one-liner", SHL, "hello\n", true},
+		{"dheredoc one-liner", DHEREDOC, "hello\n", true},
+		{"wrong op", LSS, "hello\n", false},
+		{"multi-line", SHL, "a\nb\n", false},
+		{"no trailing newline", SHL, "hello", false},
+		{"dollar expansion", SHL, "$HOME\n", false},
+		{"backtick", SHL, "`x`\n", false},
+		{"double quote", SHL, `say "hi"` + "\n", false},
+		{"backslash", SHL, `a\b` + "\n", false},
+		{"glob-looking but safe", SHL, "foo *.txt\n", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Redirect{Op: tc.op, Hdoc: &Lit{Value: tc.body}}
+			if got := minifiableHdoc(r); got != tc.want {
+				t.Errorf("minifiableHdoc(%q) = %v, want %v", tc.body, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestElasticWriterAlignsOperatorColumn(t *testing.T) {
+	var buf bytes.Buffer
+	e := newElasticWriter(&buf, 1)
+	lines := []string{
+		"a" + string(elasticSentinel) + "=1\n",
+		"ccc" + string(elasticSentinel) + "=22\n",
+	}
+	for _, line := range lines {
+		if _, err := e.WriteString(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.flushBlock()
+	// The sentinel sits before the operator, so padding the name cell
+	// out to the block's widest name is what lines up the "=" signs -
+	// not wherever the value happens to start.
+	want := "a   =1\nccc =22\n"
+	if got := buf.String(); got != want {
+		t.Errorf("elasticWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestElasticWriterBreaksBlockOnBlankLine(t *testing.T) {
+	var buf bytes.Buffer
+	e := newElasticWriter(&buf, 1)
+	lines := []string{
+		"a" + string(elasticSentinel) + "=1\n",
+		"\n",
+		"ccc" + string(elasticSentinel) + "=22\n",
+	}
+	for _, line := range lines {
+		if _, err := e.WriteString(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.flushBlock()
+	// A blank line ends the current block, so the two assignments are
+	// aligned independently rather than against each other.
+	want := "a =1\n\nccc =22\n"
+	if got := buf.String(); got != want {
+		t.Errorf("elasticWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestGroupFillMode(t *testing.T) {
+	fill := func(p *printer) {
+		p.openGroup(groupInconsistent)
+		p.writeString("aaaa")
+		p.brk(breakSpace)
+		p.writeString("bbbb")
+		p.brk(breakSpace)
+		p.writeString("cccc")
+		p.closeGroup()
+	}
+	tests := []struct {
+		name         string
+		maxLineWidth int
+		want         string
+	}{
+		{"fits within the margin", 100, "aaaa bbbb cccc"},
+		{"breaks once the margin is reached", 8, "aaaa bbbb\n  cccc"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := &printer{out: &buf}
+			p.c.MaxLineWidth = tc.maxLineWidth
+			p.c.Spaces = 2
+			fill(p)
+			if got := buf.String(); got != tc.want {
+				t.Errorf("group output = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}